@@ -20,39 +20,78 @@ typedef struct {
 import "C"
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 	"unsafe"
 )
 
 // Diagnostic represents a TypeScript diagnostic
 type Diagnostic struct {
-	Message  string `json:"message"`
-	Line     int    `json:"line"`
-	Column   int    `json:"column"`
+	Message string `json:"message"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	// Length is the diagnostic's span in UTF-16 code units, i.e. how far
+	// past Line/Column the squiggle should extend.
+	Length int `json:"length,omitempty"`
+
 	Code     string `json:"code"`
 	Severity int    `json:"severity"`
+
+	// Category is tsgo's own classification ("error", "warning",
+	// "suggestion", "message"), kept alongside Severity for callers that
+	// want the finer-grained value.
+	Category string `json:"category,omitempty"`
+	// Source identifies what produced the diagnostic, e.g. "tsgo".
+	Source string `json:"source,omitempty"`
+	// RelatedInformation carries the secondary locations tsgo attaches to
+	// a diagnostic, e.g. "'x' is declared here." pointing at another file.
+	RelatedInformation []DiagnosticLocation `json:"relatedInformation,omitempty"`
+}
+
+// DiagnosticLocation is a single file/position/message triple, used for
+// Diagnostic.RelatedInformation.
+type DiagnosticLocation struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
 }
 
 // CheckResult represents the result of type checking
 type CheckResult struct {
 	Diagnostics []Diagnostic `json:"diagnostics"`
-	Error       string       `json:"error,omitempty"`
+	// RelatedDiagnostics holds errors surfaced by a project-aware check
+	// (tsgo_check_in_project) that point at files other than the one
+	// requested, e.g. a type error in an imported module. tsgo_check_batch
+	// leaves it empty and drops diagnostics for files outside the batch.
+	RelatedDiagnostics []Diagnostic `json:"relatedDiagnostics,omitempty"`
+	Error              string       `json:"error,omitempty"`
 }
 
-var diagnosticPattern = regexp.MustCompile(`^(.+)\((\d+),(\d+)\): (error|warning) (TS\d+): (.+)$`)
-
 //export tsgo_check
 func tsgo_check(content *C.char, filename *C.char) *C.char {
 	goContent := C.GoString(content)
 	goFilename := C.GoString(filename)
 
-	result := checkTypeScript(goContent, goFilename)
+	var result CheckResult
+	if pool := activePool(); pool != nil {
+		result = pool.check(goContent, goFilename)
+	} else {
+		result = checkTypeScript(goContent, goFilename)
+	}
 
 	jsonBytes, err := json.Marshal(result)
 	if err != nil {
@@ -67,6 +106,205 @@ func tsgo_free(ptr *C.char) {
 	C.free(unsafe.Pointer(ptr))
 }
 
+//export tsgo_pool_init
+func tsgo_pool_init(size C.int) *C.char {
+	pool, err := newTsgoWorkerPool(int(size))
+	if err != nil {
+		return C.CString(err.Error())
+	}
+
+	poolMu.Lock()
+	old := globalPool
+	globalPool = pool
+	poolMu.Unlock()
+
+	if old != nil {
+		old.shutdown()
+	}
+
+	return C.CString("")
+}
+
+//export tsgo_pool_shutdown
+func tsgo_pool_shutdown() {
+	poolMu.Lock()
+	old := globalPool
+	globalPool = nil
+	poolMu.Unlock()
+
+	if old != nil {
+		old.shutdown()
+	}
+}
+
+// poolMu guards globalPool, which tsgo_check consults to decide whether to
+// route through the warm worker pool or fall back to a per-call spawn.
+var (
+	poolMu     sync.Mutex
+	globalPool *tsgoWorkerPool
+)
+
+func activePool() *tsgoWorkerPool {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+	return globalPool
+}
+
+// watchStatusMarker is the line tsgo's `--watch` mode prints once a
+// recompile settles (mirroring tsc --watch's "Found N errors. Watching for
+// file changes." status line), signalling that every diagnostic for the
+// change that triggered it has already been written to stdout.
+const watchStatusMarker = "Watching for file changes."
+
+// tsgoWorker is a single long-lived `tsgo --watch` subprocess watching one
+// dedicated overlay file on disk. A check rewrites that file's contents,
+// which tsgo's watcher picks up like any other file-system edit, and reads
+// the resulting `--pretty false` diagnostics back off stdout until the
+// watch-status line marks the recompile as done. This avoids paying
+// TypeScript's process-startup cost on every call.
+type tsgoWorker struct {
+	cmd     *exec.Cmd
+	tmpFile string
+	stdout  *bufio.Reader
+	mu      sync.Mutex
+}
+
+var workerSeq uint64
+
+func spawnTsgoWorker() (*tsgoWorker, error) {
+	tmpFile := filepath.Join(os.TempDir(), "vize-tsgo-pool-"+strconv.FormatUint(atomic.AddUint64(&workerSeq, 1), 10)+".ts")
+	if err := os.WriteFile(tmpFile, nil, 0644); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("tsgo", "--watch", "--noEmit", "--skipLibCheck", "--pretty", "false", tmpFile)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		os.Remove(tmpFile)
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		os.Remove(tmpFile)
+		return nil, err
+	}
+
+	worker := &tsgoWorker{
+		cmd:     cmd,
+		tmpFile: tmpFile,
+		stdout:  bufio.NewReader(stdout),
+	}
+
+	// Drain the initial compile tsgo runs on startup against the empty
+	// placeholder file before this worker takes its first real check.
+	worker.drainUntilIdle()
+
+	return worker, nil
+}
+
+// drainUntilIdle reads diagnostic lines off the worker's stdout until the
+// watch-status line appears, returning everything emitted for the most
+// recent recompile.
+func (w *tsgoWorker) drainUntilIdle() []Diagnostic {
+	var diagnostics []Diagnostic
+
+	for {
+		line, err := w.stdout.ReadString('\n')
+		trimmed := strings.TrimSpace(line)
+
+		if trimmed != "" {
+			if strings.Contains(trimmed, watchStatusMarker) {
+				return diagnostics
+			}
+			if strings.HasPrefix(trimmed, "{") {
+				var raw tsgoRawDiagnostic
+				if jsonErr := json.Unmarshal([]byte(trimmed), &raw); jsonErr == nil {
+					diagnostics = append(diagnostics, diagnosticFromRaw(raw))
+				}
+			}
+		}
+
+		if err != nil {
+			return diagnostics
+		}
+	}
+}
+
+func (w *tsgoWorker) check(content, filename string) CheckResult {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := os.WriteFile(w.tmpFile, []byte(content), 0644); err != nil {
+		return CheckResult{Error: "worker overlay write failed: " + err.Error()}
+	}
+
+	diagnostics := w.drainUntilIdle()
+	for i := range diagnostics {
+		diagnostics[i].File = filename
+	}
+
+	return CheckResult{Diagnostics: diagnostics}
+}
+
+func (w *tsgoWorker) close() {
+	if w.cmd.Process != nil {
+		w.cmd.Process.Kill()
+	}
+	_ = w.cmd.Wait()
+	os.Remove(w.tmpFile)
+}
+
+// tsgoWorkerPool multiplexes tsgo_check calls across a fixed set of warm
+// tsgoWorkers in round-robin order.
+type tsgoWorkerPool struct {
+	workers []*tsgoWorker
+	nextIdx uint64
+}
+
+func newTsgoWorkerPool(size int) (*tsgoWorkerPool, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("tsgo pool size must be positive, got %d", size)
+	}
+
+	pool := &tsgoWorkerPool{}
+	for i := 0; i < size; i++ {
+		worker, err := spawnTsgoWorker()
+		if err != nil {
+			pool.shutdown()
+			return nil, fmt.Errorf("spawning tsgo worker %d: %w", i, err)
+		}
+		pool.workers = append(pool.workers, worker)
+	}
+
+	return pool, nil
+}
+
+func (p *tsgoWorkerPool) check(content, filename string) CheckResult {
+	idx := atomic.AddUint64(&p.nextIdx, 1) % uint64(len(p.workers))
+
+	return p.workers[idx].check(content, filename)
+}
+
+func (p *tsgoWorkerPool) shutdown() {
+	for _, worker := range p.workers {
+		worker.close()
+	}
+}
+
+// standaloneTsgoArgs are the flags used to check a single file in isolation,
+// shared by checkTypeScript and checkTypeScriptWithCtx so the two never
+// silently drift apart.
+var standaloneTsgoArgs = []string{
+	"--noEmit",
+	"--skipLibCheck",
+	"--strict",
+	"--target", "ESNext",
+	"--module", "ESNext",
+	"--moduleResolution", "bundler",
+	"--pretty", "false",
+}
+
 func checkTypeScript(content, filename string) CheckResult {
 	// Create temp file
 	tmpDir := os.TempDir()
@@ -77,57 +315,593 @@ func checkTypeScript(content, filename string) CheckResult {
 	}
 	defer os.Remove(tmpFile)
 
-	// Run tsgo
-	cmd := exec.Command("tsgo",
-		"--noEmit",
-		"--skipLibCheck",
-		"--strict",
-		"--target", "ESNext",
-		"--module", "ESNext",
-		"--moduleResolution", "bundler",
-		tmpFile,
-	)
+	cmd := exec.Command("tsgo", append(append([]string{}, standaloneTsgoArgs...), tmpFile)...)
 
 	output, _ := cmd.CombinedOutput()
 
-	// Parse diagnostics
-	diagnostics := parseDiagnostics(string(output), tmpFile, filename)
+	diagnostics := parseTsgoOutput(string(output))
 
 	return CheckResult{Diagnostics: diagnostics}
 }
 
-func parseDiagnostics(output, tmpFile, originalFile string) []Diagnostic {
+// tsgoRawDiagnostic mirrors one line of tsgo's `--pretty false` output: a
+// self-contained JSON object per diagnostic rather than the single-line
+// "file(line,col): error TSxxxx: message" text meant for a terminal.
+type tsgoRawDiagnostic struct {
+	File        string              `json:"file"`
+	Line        int                 `json:"line"`
+	Character   int                 `json:"character"`
+	Length      int                 `json:"length"`
+	Category    string              `json:"category"`
+	Code        int                 `json:"code"`
+	MessageText string              `json:"messageText"`
+	Source      string              `json:"source,omitempty"`
+	Related     []tsgoRawDiagnostic `json:"relatedInformation,omitempty"`
+}
+
+// parseTsgoOutput parses tsgo's `--pretty false` output, one JSON diagnostic
+// per line, tolerating any interleaved plain-text lines (build progress,
+// summary counts) by skipping anything that isn't a JSON object.
+func parseTsgoOutput(output string) []Diagnostic {
 	var diagnostics []Diagnostic
 
-	lines := strings.Split(output, "\n")
-	for _, line := range lines {
+	for _, line := range strings.Split(output, "\n") {
 		line = strings.TrimSpace(line)
-		if line == "" {
+		if !strings.HasPrefix(line, "{") {
 			continue
 		}
 
-		matches := diagnosticPattern.FindStringSubmatch(line)
-		if len(matches) == 7 {
-			lineNum, _ := strconv.Atoi(matches[2])
-			colNum, _ := strconv.Atoi(matches[3])
-			severity := 1
-			if matches[4] == "warning" {
-				severity = 2
-			}
-
-			diagnostics = append(diagnostics, Diagnostic{
-				Message:  matches[6],
-				Line:     lineNum,
-				Column:   colNum,
-				Code:     matches[5],
-				Severity: severity,
-			})
+		var raw tsgoRawDiagnostic
+		if err := json.Unmarshal([]byte(line), &raw); err != nil {
+			continue
 		}
+
+		diagnostics = append(diagnostics, diagnosticFromRaw(raw))
 	}
 
 	return diagnostics
 }
 
+// severityFromCategory maps tsgo's Category ("error", "warning",
+// "suggestion", "message") onto the Severity int other Diagnostic consumers
+// key off. Anything unrecognized falls back to "error" rather than silently
+// matching a category tsgo hasn't been observed to emit yet.
+func severityFromCategory(category string) int {
+	switch category {
+	case "warning":
+		return 2
+	case "suggestion":
+		return 3
+	case "message":
+		return 4
+	default:
+		return 1
+	}
+}
+
+func diagnosticFromRaw(raw tsgoRawDiagnostic) Diagnostic {
+	severity := severityFromCategory(raw.Category)
+
+	var related []DiagnosticLocation
+	for _, r := range raw.Related {
+		related = append(related, DiagnosticLocation{
+			File:    r.File,
+			Line:    r.Line,
+			Column:  r.Character,
+			Message: r.MessageText,
+		})
+	}
+
+	return Diagnostic{
+		Message:            raw.MessageText,
+		File:               raw.File,
+		Line:               raw.Line,
+		Column:             raw.Character,
+		Length:             raw.Length,
+		Code:               "TS" + strconv.Itoa(raw.Code),
+		Severity:           severity,
+		Category:           raw.Category,
+		Source:             raw.Source,
+		RelatedInformation: related,
+	}
+}
+
+//export tsgo_check_in_project
+func tsgo_check_in_project(content *C.char, filename *C.char, projectRoot *C.char) *C.char {
+	goContent := C.GoString(content)
+	goFilename := C.GoString(filename)
+	goProjectRoot := C.GoString(projectRoot)
+
+	result := checkTypeScriptInProject(goContent, goFilename, goProjectRoot)
+
+	jsonBytes, err := json.Marshal(result)
+	if err != nil {
+		return C.CString(`{"error":"failed to marshal result"}`)
+	}
+
+	return C.CString(string(jsonBytes))
+}
+
+// findTsconfig walks upward from root looking for the nearest tsconfig.json,
+// the same resolution order tsc/tsgo use when given a directory via -p.
+func findTsconfig(root string) (string, error) {
+	dir := root
+	for {
+		candidate := filepath.Join(dir, "tsconfig.json")
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("no tsconfig.json found above %s", root)
+		}
+		dir = parent
+	}
+}
+
+// overlayLocksMu guards overlayLocks, a per-absolute-path mutex so two
+// concurrent checks against the same on-disk file (checkTypeScriptInProject,
+// checkTypeScriptBatch) can't interleave their backup/write/restore and
+// leave the user's real file holding a transient overlay buffer.
+var (
+	overlayLocksMu sync.Mutex
+	overlayLocks   = make(map[string]*sync.Mutex)
+)
+
+func overlayLockFor(absFilename string) *sync.Mutex {
+	overlayLocksMu.Lock()
+	defer overlayLocksMu.Unlock()
+
+	lock, ok := overlayLocks[absFilename]
+	if !ok {
+		lock = &sync.Mutex{}
+		overlayLocks[absFilename] = lock
+	}
+	return lock
+}
+
+// lockOverlay acquires the overlay lock for filename, held for the duration
+// of one overlay-write/check/restore cycle.
+func lockOverlay(filename string) *sync.Mutex {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		abs = filename
+	}
+
+	lock := overlayLockFor(abs)
+	lock.Lock()
+	return lock
+}
+
+// lockOverlays acquires the overlay lock for every filename, in sorted
+// order, so two concurrent batches touching overlapping filenames always
+// acquire their shared locks in the same order and can't deadlock.
+func lockOverlays(filenames []string) []*sync.Mutex {
+	abs := make([]string, len(filenames))
+	for i, f := range filenames {
+		a, err := filepath.Abs(f)
+		if err != nil {
+			a = f
+		}
+		abs[i] = a
+	}
+	sort.Strings(abs)
+
+	locks := make([]*sync.Mutex, 0, len(abs))
+	seen := make(map[string]bool, len(abs))
+	for _, a := range abs {
+		if seen[a] {
+			continue
+		}
+		seen[a] = true
+
+		lock := overlayLockFor(a)
+		lock.Lock()
+		locks = append(locks, lock)
+	}
+
+	return locks
+}
+
+func unlockAll(locks []*sync.Mutex) {
+	for _, lock := range locks {
+		lock.Unlock()
+	}
+}
+
+// checkTypeScriptInProject checks filename as part of its real project
+// rather than in isolation. It overlays the unsaved content onto filename's
+// own path for the duration of the run (backing up and restoring whatever
+// was on disk) so tsgo's module resolution sees the actual program: real
+// tsconfig compilerOptions, path aliases, and cross-file imports.
+func checkTypeScriptInProject(content, filename, projectRoot string) CheckResult {
+	tsconfigPath, err := findTsconfig(projectRoot)
+	if err != nil {
+		return CheckResult{Error: err.Error()}
+	}
+
+	lock := lockOverlay(filename)
+	defer lock.Unlock()
+
+	original, readErr := os.ReadFile(filename)
+	hadOriginal := readErr == nil
+
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		return CheckResult{Error: "failed to write overlay: " + err.Error()}
+	}
+	defer func() {
+		if hadOriginal {
+			os.WriteFile(filename, original, 0644)
+		} else {
+			os.Remove(filename)
+		}
+	}()
+
+	output, err := runBoundedTsgo(filepath.Dir(tsconfigPath), workspaceCheckTimeout, "-p", tsconfigPath, "--noEmit", "--pretty", "false")
+	if err == context.DeadlineExceeded {
+		return CheckResult{Error: "deadline exceeded"}
+	}
+	if err != nil {
+		if _, isExitErr := err.(*exec.ExitError); !isExitErr {
+			return CheckResult{Error: err.Error()}
+		}
+	}
+
+	target, related := splitDiagnosticsByFile(parseTsgoOutput(string(output)), filename)
+	return CheckResult{Diagnostics: target, RelatedDiagnostics: related}
+}
+
+// splitDiagnosticsByFile partitions a project-wide tsgo run's diagnostics
+// into those that point at targetFile and those that point elsewhere (e.g. a
+// type error surfaced through an import targetFile pulled in).
+func splitDiagnosticsByFile(diagnostics []Diagnostic, targetFile string) (target, related []Diagnostic) {
+	absTarget, err := filepath.Abs(targetFile)
+	if err != nil {
+		absTarget = targetFile
+	}
+
+	for _, diag := range diagnostics {
+		absFile, err := filepath.Abs(diag.File)
+		if err != nil {
+			absFile = diag.File
+		}
+
+		if absFile == absTarget {
+			target = append(target, diag)
+		} else {
+			related = append(related, diag)
+		}
+	}
+
+	return target, related
+}
+
+// batchFile is one entry of tsgo_check_batch's input array.
+type batchFile struct {
+	Filename string `json:"filename"`
+	Content  string `json:"content"`
+}
+
+// BatchCheckResult is one entry of tsgo_check_batch's output array, pairing
+// a CheckResult back up with the filename it belongs to.
+type BatchCheckResult struct {
+	Filename string `json:"filename"`
+	CheckResult
+}
+
+//export tsgo_check_batch
+func tsgo_check_batch(filesJSON *C.char) *C.char {
+	goFilesJSON := C.GoString(filesJSON)
+
+	var files []batchFile
+	if err := json.Unmarshal([]byte(goFilesJSON), &files); err != nil {
+		return C.CString(`{"error":"failed to parse files JSON"}`)
+	}
+
+	results := checkTypeScriptBatch(files)
+
+	jsonBytes, err := json.Marshal(results)
+	if err != nil {
+		return C.CString(`{"error":"failed to marshal result"}`)
+	}
+
+	return C.CString(string(jsonBytes))
+}
+
+// fileBackup records what was on disk at filename before checkTypeScriptBatch
+// overlaid it, so the overlay can be undone afterwards.
+type fileBackup struct {
+	filename string
+	content  []byte
+	existed  bool
+}
+
+func restoreFileBackups(backups []fileBackup) {
+	for _, b := range backups {
+		if b.existed {
+			os.WriteFile(b.filename, b.content, 0644)
+		} else {
+			os.Remove(b.filename)
+		}
+	}
+}
+
+// uniformBatchError builds a full-length BatchCheckResult slice for a
+// failure that aborts the whole batch. failedFile (if non-empty) gets msg
+// verbatim; every other entry gets a "batch aborted" error referencing it,
+// so callers can tell which file actually caused the failure.
+func uniformBatchError(files []batchFile, failedFile, msg string) []BatchCheckResult {
+	results := make([]BatchCheckResult, len(files))
+	for i, f := range files {
+		if failedFile == "" || f.Filename == failedFile {
+			results[i] = BatchCheckResult{Filename: f.Filename, CheckResult: CheckResult{Error: msg}}
+		} else {
+			results[i] = BatchCheckResult{Filename: f.Filename, CheckResult: CheckResult{Error: "batch aborted: " + msg}}
+		}
+	}
+	return results
+}
+
+// checkTypeScriptBatch type-checks many files in a single tsgo invocation so
+// callers checking a whole workspace (save-all, a lint sweep) don't pay N
+// startup costs. Each file's unsaved content is overlaid onto its own path
+// for the duration of the run, same as checkTypeScriptInProject, and the
+// invocation itself runs under workspaceCheckTimeout and shares
+// checkTypeScriptWithCtx's parallelism cap via runBoundedTsgo so a hung tsgo
+// can't pin down every overlaid file indefinitely.
+func checkTypeScriptBatch(files []batchFile) []BatchCheckResult {
+	filenames := make([]string, len(files))
+	for i, f := range files {
+		filenames[i] = f.Filename
+	}
+
+	locks := lockOverlays(filenames)
+	defer unlockAll(locks)
+
+	backups := make([]fileBackup, 0, len(files))
+
+	for _, f := range files {
+		original, readErr := os.ReadFile(f.Filename)
+		backups = append(backups, fileBackup{filename: f.Filename, content: original, existed: readErr == nil})
+
+		if err := os.WriteFile(f.Filename, []byte(f.Content), 0644); err != nil {
+			restoreFileBackups(backups)
+			return uniformBatchError(files, f.Filename, "failed to write overlay: "+err.Error())
+		}
+	}
+	defer restoreFileBackups(backups)
+
+	output, err := runBoundedTsgo("", workspaceCheckTimeout, append(append([]string{}, standaloneTsgoArgs...), filenames...)...)
+	if err == context.DeadlineExceeded {
+		return uniformBatchError(files, "", "deadline exceeded")
+	}
+	if err != nil {
+		if _, isExitErr := err.(*exec.ExitError); !isExitErr {
+			return uniformBatchError(files, "", err.Error())
+		}
+	}
+
+	diagnostics := parseTsgoOutput(string(output))
+	byFile := make(map[string][]Diagnostic, len(files))
+	for _, diag := range diagnostics {
+		abs, err := filepath.Abs(diag.File)
+		if err != nil {
+			abs = diag.File
+		}
+		byFile[abs] = append(byFile[abs], diag)
+	}
+
+	results := make([]BatchCheckResult, 0, len(files))
+	for _, f := range files {
+		abs, err := filepath.Abs(f.Filename)
+		if err != nil {
+			abs = f.Filename
+		}
+		results = append(results, BatchCheckResult{
+			Filename:    f.Filename,
+			CheckResult: CheckResult{Diagnostics: byFile[abs]},
+		})
+	}
+
+	return results
+}
+
+// cancelGracePeriod is how long a canceled or timed-out tsgo gets to exit
+// after SIGTERM before exec escalates to SIGKILL (via cmd.WaitDelay).
+const cancelGracePeriod = 2 * time.Second
+
+// defaultMaxParallel bounds how many tsgo_check_with_ctx checks run at once
+// before tsgo_set_max_parallel is called.
+const defaultMaxParallel = 4
+
+var (
+	parallelMu  sync.Mutex
+	parallelSem = make(chan struct{}, defaultMaxParallel)
+)
+
+//export tsgo_set_max_parallel
+func tsgo_set_max_parallel(n C.int) {
+	size := int(n)
+	if size <= 0 {
+		size = 1
+	}
+
+	parallelMu.Lock()
+	parallelSem = make(chan struct{}, size)
+	parallelMu.Unlock()
+}
+
+func acquireParallelSlot() chan struct{} {
+	parallelMu.Lock()
+	sem := parallelSem
+	parallelMu.Unlock()
+
+	sem <- struct{}{}
+	return sem
+}
+
+func releaseParallelSlot(sem chan struct{}) {
+	<-sem
+}
+
+// workspaceCheckTimeout bounds how long a project-wide or batch tsgo
+// invocation may run before it's killed. Unlike tsgo_check_with_ctx these
+// callers overlay real on-disk files for the duration of the run, so an
+// unbounded hang would leave the user's file stuck holding draft content
+// indefinitely.
+const workspaceCheckTimeout = 60 * time.Second
+
+// runBoundedTsgo runs tsgo with args under dir, sharing the same
+// parallelism cap and process-group kill machinery as
+// checkTypeScriptWithCtx: it won't run alongside more than
+// tsgo_set_max_parallel other checks, and it's killed outright if it
+// outlives timeout. A timeout or signal kill is reported as
+// context.DeadlineExceeded; any other failure is returned as-is.
+func runBoundedTsgo(dir string, timeout time.Duration, args ...string) ([]byte, error) {
+	sem := acquireParallelSlot()
+	defer releaseParallelSlot(sem)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "tsgo", args...)
+	cmd.Dir = dir
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+	}
+	cmd.WaitDelay = cancelGracePeriod
+
+	output, err := cmd.CombinedOutput()
+	if killedBySignal(err) || ctx.Err() != nil {
+		return output, context.DeadlineExceeded
+	}
+
+	return output, err
+}
+
+// trackedCheck is an in-flight tsgo_check_with_ctx invocation, keyed by its
+// caller-assigned requestID so tsgo_cancel can find and stop it.
+type trackedCheck struct {
+	cancel   context.CancelFunc
+	canceled atomic.Bool
+}
+
+var (
+	outstandingMu sync.Mutex
+	outstanding   = make(map[uint64]*trackedCheck)
+)
+
+//export tsgo_cancel
+func tsgo_cancel(requestID C.ulonglong) {
+	outstandingMu.Lock()
+	tracked, ok := outstanding[uint64(requestID)]
+	outstandingMu.Unlock()
+	if !ok {
+		return
+	}
+
+	// Mark canceled before triggering the context so checkTypeScriptWithCtx
+	// can tell a deliberate cancel apart from the timeout firing.
+	tracked.canceled.Store(true)
+	tracked.cancel()
+}
+
+//export tsgo_check_with_ctx
+func tsgo_check_with_ctx(content *C.char, filename *C.char, requestID C.ulonglong, timeoutMS C.int) *C.char {
+	goContent := C.GoString(content)
+	goFilename := C.GoString(filename)
+
+	result := checkTypeScriptWithCtx(goContent, goFilename, uint64(requestID), time.Duration(timeoutMS)*time.Millisecond)
+
+	jsonBytes, err := json.Marshal(result)
+	if err != nil {
+		return C.CString(`{"error":"failed to marshal result"}`)
+	}
+
+	return C.CString(string(jsonBytes))
+}
+
+// checkTypeScriptWithCtx is checkTypeScript with a deadline and a cancel
+// hook: it registers its context's cancel func under requestID so a
+// concurrent tsgo_cancel can stop it, runs the subprocess in its own process
+// group (via Setpgid) so the SIGTERM sent on cancel or deadline reaches any
+// children tsgo spawned as well, and bounds how many such checks run at once
+// via the tsgo_set_max_parallel semaphore. Nothing signals that group when
+// the host process itself exits, so a check still running then is not
+// stopped.
+func checkTypeScriptWithCtx(content, filename string, requestID uint64, timeout time.Duration) CheckResult {
+	sem := acquireParallelSlot()
+	defer releaseParallelSlot(sem)
+
+	tmpDir := os.TempDir()
+	tmpFile := filepath.Join(tmpDir, "vize-tsgo-"+strconv.FormatUint(requestID, 10)+".ts")
+
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		return CheckResult{Error: "failed to write temp file: " + err.Error()}
+	}
+	defer os.Remove(tmpFile)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "tsgo", append(append([]string{}, standaloneTsgoArgs...), tmpFile)...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	// On ctx cancellation (deadline or tsgo_cancel), signal the process
+	// group gently first; exec escalates to Kill on its own if the process
+	// hasn't exited by WaitDelay.
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+	}
+	cmd.WaitDelay = cancelGracePeriod
+
+	tracked := &trackedCheck{cancel: cancel}
+	outstandingMu.Lock()
+	outstanding[requestID] = tracked
+	outstandingMu.Unlock()
+	defer func() {
+		outstandingMu.Lock()
+		delete(outstanding, requestID)
+		outstandingMu.Unlock()
+	}()
+
+	output, err := cmd.CombinedOutput()
+
+	// A signaled process is our own SIGTERM/SIGKILL; ctx.Err() being set
+	// also catches the case where the context was already done before
+	// cmd.Start() even ran (e.g. timeoutMS is 0, or tsgo_cancel fires in
+	// the gap between registering and starting), where Start itself
+	// returns ctx.Err() directly rather than an *exec.ExitError.
+	if killedBySignal(err) || ctx.Err() != nil {
+		if tracked.canceled.Load() {
+			return CheckResult{Error: "canceled"}
+		}
+		return CheckResult{Error: "deadline exceeded"}
+	}
+
+	if err != nil {
+		if _, isExitErr := err.(*exec.ExitError); !isExitErr {
+			return CheckResult{Error: err.Error()}
+		}
+	}
+
+	return CheckResult{Diagnostics: parseTsgoOutput(string(output))}
+}
+
+// killedBySignal reports whether err is the *exec.ExitError produced by a
+// process that was terminated by a signal (our own SIGTERM/SIGKILL), as
+// opposed to one that simply exited non-zero because it found diagnostics.
+func killedBySignal(err error) bool {
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return false
+	}
+
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled()
+}
+
 func main() {
 	// This is required for building as a C shared library
 	// but will never be called